@@ -0,0 +1,153 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpretry retries HTTP requests with exponential backoff and
+// jitter, so transient Cloudflare gateway errors don't need to be
+// handled ad-hoc at every call site.
+package httpretry
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+const (
+	defaultBaseDelay      = 500 * time.Millisecond
+	defaultMaxDelay       = 30 * time.Second
+	defaultMaxElapsedTime = 2 * time.Minute
+)
+
+// Config tunes the backoff schedule. Zero values fall back to sane
+// defaults.
+type Config struct {
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	MaxElapsedTime time.Duration
+}
+
+// DefaultConfig returns the backoff schedule used when no Config is given.
+func DefaultConfig() Config {
+	return Config{
+		BaseDelay:      defaultBaseDelay,
+		MaxDelay:       defaultMaxDelay,
+		MaxElapsedTime: defaultMaxElapsedTime,
+	}
+}
+
+// Do sends requests built by newRequest, retrying on network errors and
+// on 429/502/503/504 responses until one succeeds or cfg.MaxElapsedTime
+// is exceeded. newRequest is called again on every attempt since request
+// bodies aren't rewindable. If client is nil, http.DefaultClient is used.
+func Do(client *http.Client, newRequest func() (*http.Request, error), cfg Config) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultMaxDelay
+	}
+	if cfg.MaxElapsedTime <= 0 {
+		cfg.MaxElapsedTime = defaultMaxElapsedTime
+	}
+
+	deadline := time.Now().Add(cfg.MaxElapsedTime)
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := client.Do(req)
+		if err == nil && !retryableStatus(res.StatusCode) {
+			return res, nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("httpretry: received retryable status %d", res.StatusCode)
+		}
+
+		delay := retryAfter(res)
+		if delay <= 0 {
+			delay = backoff(cfg, attempt)
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("httpretry: giving up after %d attempts: %w", attempt+1, lastErr)
+		}
+
+		log.Printf("httpretry: attempt %d failed (%v), retrying in %s", attempt+1, lastErr, delay)
+		time.Sleep(delay)
+	}
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header, if present, as either delay
+// seconds or an HTTP-date. It returns 0 if res is nil or the header is
+// absent/unparseable.
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+
+	h := res.Header.Get("Retry-After")
+	if h == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(h); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
+
+// backoff computes an exponential delay for attempt, capped at
+// cfg.MaxDelay, with up to 50% jitter to avoid thundering-herd retries.
+func backoff(cfg Config, attempt int) time.Duration {
+	d := cfg.BaseDelay * time.Duration(uint64(1)<<uint(attempt))
+	if d <= 0 || d > cfg.MaxDelay {
+		d = cfg.MaxDelay
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(d) + 1))
+	return d/2 + jitter/2
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpretry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		name   string
+		res    *http.Response
+		want   time.Duration
+		approx bool
+	}{
+		{
+			name: "nil response",
+			res:  nil,
+			want: 0,
+		},
+		{
+			name: "no header",
+			res:  &http.Response{Header: http.Header{}},
+			want: 0,
+		},
+		{
+			name: "delay-seconds",
+			res:  &http.Response{Header: http.Header{"Retry-After": []string{"5"}}},
+			want: 5 * time.Second,
+		},
+		{
+			name:   "http-date",
+			res:    &http.Response{Header: http.Header{"Retry-After": []string{time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)}}},
+			want:   10 * time.Second,
+			approx: true,
+		},
+		{
+			name: "unparseable",
+			res:  &http.Response{Header: http.Header{"Retry-After": []string{"not-a-value"}}},
+			want: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := retryAfter(c.res)
+			if c.approx {
+				diff := got - c.want
+				if diff < -time.Second || diff > time.Second {
+					t.Errorf("retryAfter() = %s, want ~%s", got, c.want)
+				}
+				return
+			}
+			if got != c.want {
+				t.Errorf("retryAfter() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := Config{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	cases := []struct {
+		attempt  int
+		wantCeil time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{10, time.Second}, // capped at MaxDelay well before attempt 10
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			d := backoff(cfg, c.attempt)
+			if d < 0 {
+				t.Fatalf("backoff(attempt=%d) = %s, want >= 0", c.attempt, d)
+			}
+			if d > c.wantCeil {
+				t.Fatalf("backoff(attempt=%d) = %s, want <= %s", c.attempt, d, c.wantCeil)
+			}
+		}
+	}
+}
+
+func TestBackoffNeverExceedsMaxDelay(t *testing.T) {
+	cfg := Config{BaseDelay: time.Second, MaxDelay: 2 * time.Second}
+
+	for attempt := 0; attempt < 40; attempt++ {
+		for i := 0; i < 20; i++ {
+			if d := backoff(cfg, attempt); d > cfg.MaxDelay {
+				t.Fatalf("backoff(attempt=%d) = %s exceeds MaxDelay %s", attempt, d, cfg.MaxDelay)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exposes Prometheus metrics and /healthz, /readyz
+// endpoints for running psbmon as a long-lived daemon.
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// PastesSeen counts every paste ID returned by the daily feed.
+	PastesSeen = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "psbmon_pastes_seen_total",
+		Help: "Total number of paste IDs observed from the psbdmp.cc daily feed.",
+	})
+
+	// PastesNew counts paste IDs not already present in storage.
+	PastesNew = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "psbmon_pastes_new_total",
+		Help: "Total number of new (previously unseen) paste IDs.",
+	})
+
+	// FetchErrors counts failures by pipeline stage.
+	FetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "psbmon_fetch_errors_total",
+		Help: "Total number of errors encountered, labeled by stage.",
+	}, []string{"stage"})
+
+	// FetchDuration tracks how long each HTTP round trip takes.
+	FetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "psbmon_fetch_duration_seconds",
+		Help: "Duration of HTTP calls made by psbmon, labeled by stage.",
+	}, []string{"stage"})
+
+	// LastSuccess records the unix timestamp of the last fully
+	// successful poll of the daily feed.
+	LastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "psbmon_last_success_timestamp_seconds",
+		Help: "Unix timestamp of the last successful poll of the daily feed.",
+	})
+
+	lastSuccessMu   sync.Mutex
+	lastSuccessTime time.Time
+)
+
+// RecordSuccess marks now as the time of the last successful poll,
+// updating both the in-process value used by /readyz and the
+// LastSuccess gauge scraped by Prometheus.
+func RecordSuccess(now time.Time) {
+	lastSuccessMu.Lock()
+	lastSuccessTime = now
+	lastSuccessMu.Unlock()
+
+	LastSuccess.Set(float64(now.Unix()))
+}
+
+// ObserveDuration records d against FetchDuration for stage. It is meant
+// to be used with time.Since and a defer at the top of a call site:
+//
+//	defer metrics.ObserveDuration("daily", time.Now())
+func ObserveDuration(stage string, start time.Time) {
+	FetchDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+}
+
+// newMux builds the /metrics, /healthz, /readyz handler set used by
+// Serve. Split out so tests can exercise the health/readiness logic
+// without binding a real listener.
+func newMux(ping func() error, maxPollAge time.Duration) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if err := ping(); err != nil {
+			http.Error(w, fmt.Sprintf("db unreachable: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := ping(); err != nil {
+			http.Error(w, fmt.Sprintf("db unreachable: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+
+		lastSuccessMu.Lock()
+		last := lastSuccessTime
+		lastSuccessMu.Unlock()
+
+		age := time.Since(last)
+		if maxPollAge > 0 && (last.IsZero() || age > maxPollAge) {
+			http.Error(w, fmt.Sprintf("last successful poll was %s ago", age), http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintf(w, "ok, last successful poll %s ago\n", age)
+	})
+
+	return mux
+}
+
+// Serve starts the metrics/health HTTP server on addr. ping is called by
+// /healthz and /readyz to check DB connectivity; /readyz additionally
+// fails if the last successful poll is older than maxPollAge (a
+// maxPollAge <= 0 disables that check). Serve blocks; call it in its own
+// goroutine.
+func Serve(addr string, ping func() error, maxPollAge time.Duration) {
+	log.Printf("metrics server listening on %s", addr)
+	if err := http.ListenAndServe(addr, newMux(ping, maxPollAge)); err != nil {
+		log.Printf("metrics server error: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthzReadyz(t *testing.T) {
+	okPing := func() error { return nil }
+	downPing := func() error { return errors.New("connection refused") }
+
+	cases := []struct {
+		name       string
+		ping       func() error
+		lastPoll   time.Time
+		maxPollAge time.Duration
+		path       string
+		wantStatus int
+	}{
+		{"healthz, db down", downPing, time.Time{}, 0, "/healthz", 503},
+		{"healthz, db up", okPing, time.Time{}, 0, "/healthz", 200},
+		{"readyz, db down", downPing, time.Now(), time.Minute, "/readyz", 503},
+		{"readyz, db up, stale poll", okPing, time.Now().Add(-time.Hour), time.Minute, "/readyz", 503},
+		{"readyz, db up, fresh poll", okPing, time.Now(), time.Minute, "/readyz", 200},
+		{"readyz, db up, no poll yet", okPing, time.Time{}, time.Minute, "/readyz", 503},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lastSuccessMu.Lock()
+			lastSuccessTime = c.lastPoll
+			lastSuccessMu.Unlock()
+
+			mux := newMux(c.ping, c.maxPollAge)
+
+			req := httptest.NewRequest("GET", c.path, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("%s = %d, want %d (body %q)", c.path, rec.Code, c.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
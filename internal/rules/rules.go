@@ -0,0 +1,117 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rules loads regex-based alert rules and matches them against
+// paste bodies fetched from psbdmp.cc.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxMatchLen bounds how much of a matched substring is ever forwarded
+// downstream, so secrets don't end up sitting in full in a Dr.Gero event
+// or a log line.
+const maxMatchLen = 48
+
+// Rule is a single named regex alert rule, as loaded from a YAML or JSON
+// rule file.
+type Rule struct {
+	Name     string   `yaml:"name" json:"name"`
+	Pattern  string   `yaml:"pattern" json:"pattern"`
+	Severity string   `yaml:"severity" json:"severity"`
+	Tags     []string `yaml:"tags" json:"tags"`
+
+	re *regexp.Regexp
+}
+
+// Match describes a single rule hit against a paste body.
+type Match struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Excerpt  string `json:"excerpt"`
+}
+
+// Load reads a set of rules from a YAML (.yaml/.yml) or JSON (.json) file
+// and compiles each rule's pattern.
+func Load(path string) ([]*Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rules: failed to read %s: %w", path, err)
+	}
+
+	var loaded []*Rule
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &loaded)
+	case ".json":
+		err = json.Unmarshal(data, &loaded)
+	default:
+		return nil, fmt.Errorf("rules: unsupported rule file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rules: failed to parse %s: %w", path, err)
+	}
+
+	for _, r := range loaded {
+		r.re, err = regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rules: rule %q has invalid pattern: %w", r.Name, err)
+		}
+	}
+
+	return loaded, nil
+}
+
+// MatchAll runs body against every rule, returning one Match per rule that
+// hit. A rule may only match once, regardless of how many times its
+// pattern occurs in body.
+func MatchAll(ruleset []*Rule, body string) []Match {
+	var matches []Match
+	for _, r := range ruleset {
+		found := r.re.FindString(body)
+		if found == "" {
+			continue
+		}
+
+		matches = append(matches, Match{
+			Rule:     r.Name,
+			Severity: r.Severity,
+			Excerpt:  redact(found),
+		})
+	}
+
+	return matches
+}
+
+// redact truncates and partially masks a matched substring so it is
+// useful for triage without leaking the full secret.
+func redact(s string) string {
+	if len(s) > maxMatchLen {
+		s = s[:maxMatchLen]
+	}
+
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+
+	return s[:4] + strings.Repeat("*", len(s)-8) + s[len(s)-4:]
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// drGeroSink posts alerts to a Dr.Gero host's /event endpoint, the
+// original (and default) psbmon notification path.
+type drGeroSink struct {
+	host string
+}
+
+// NewDrGero builds a Sink that posts to host's /event endpoint.
+func NewDrGero(host string) Sink {
+	return &drGeroSink{host: host}
+}
+
+func (s *drGeroSink) Send(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return post(fmt.Sprintf("%s/event", s.host), nil, body)
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/BlacksunLabs/dg-psbmon/internal/httpretry"
+)
+
+// post sends body to url as a JSON POST, merging in any extra headers,
+// retrying transient failures via httpretry.
+func post(url string, headers map[string]string, body []byte) error {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "psbmon")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	}
+
+	res, err := httpretry.Do(nil, newRequest, httpretry.DefaultConfig())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return nil
+}
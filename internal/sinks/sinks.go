@@ -0,0 +1,172 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sinks delivers alerts to one or more configurable
+// destinations: Dr.Gero, a generic JSON webhook, Slack, stdout, or an
+// append-only file.
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BlacksunLabs/dg-psbmon/internal/rules"
+	"gopkg.in/yaml.v3"
+)
+
+// Alert is the payload delivered to every sink when a paste is forwarded.
+type Alert struct {
+	ID      string        `json:"id"`
+	Tags    string        `json:"tags,omitempty"`
+	Rules   []string      `json:"rules,omitempty"`
+	Matches []rules.Match `json:"matches,omitempty"`
+}
+
+// Sink is a destination alerts can be delivered to.
+type Sink interface {
+	Send(a Alert) error
+}
+
+// config is the on-disk shape of a sinks config file.
+type config struct {
+	Sinks []sinkConfig `yaml:"sinks" json:"sinks"`
+}
+
+// sinkConfig describes a single configured sink and the optional
+// rule/tag filter that gates delivery to it.
+type sinkConfig struct {
+	Type    string            `yaml:"type" json:"type"`
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Secret  string            `yaml:"secret" json:"secret"`
+	Path    string            `yaml:"path" json:"path"`
+	Rules   []string          `yaml:"rules" json:"rules"`
+	Tags    []string          `yaml:"tags" json:"tags"`
+}
+
+// Load reads a YAML (.yaml/.yml) or JSON (.json) sinks config file and
+// builds the Sink for each entry, wrapped in the entry's rule/tag filter.
+func Load(path string) ([]Sink, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("sinks: failed to read %s: %w", path, err)
+	}
+
+	var cfg config
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	default:
+		return nil, fmt.Errorf("sinks: unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sinks: failed to parse %s: %w", path, err)
+	}
+
+	result := make([]Sink, 0, len(cfg.Sinks))
+	for _, sc := range cfg.Sinks {
+		s, err := newSink(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, filtered(s, sc.Rules, sc.Tags))
+	}
+
+	return result, nil
+}
+
+func newSink(sc sinkConfig) (Sink, error) {
+	switch sc.Type {
+	case "drgero":
+		return &drGeroSink{host: sc.URL}, nil
+	case "webhook":
+		return &webhookSink{url: sc.URL, headers: sc.Headers, secret: sc.Secret}, nil
+	case "slack":
+		return &slackSink{url: sc.URL}, nil
+	case "stdout":
+		return &stdoutSink{}, nil
+	case "file":
+		return &fileSink{path: sc.Path}, nil
+	default:
+		return nil, fmt.Errorf("sinks: unknown sink type %q", sc.Type)
+	}
+}
+
+// filteredSink delivers to an underlying Sink only when an Alert matches
+// the configured rule and/or tag filter. An empty filter always matches.
+type filteredSink struct {
+	Sink
+	rules map[string]bool
+	tags  map[string]bool
+}
+
+func filtered(s Sink, ruleNames, tagNames []string) Sink {
+	if len(ruleNames) == 0 && len(tagNames) == 0 {
+		return s
+	}
+
+	f := &filteredSink{Sink: s, rules: toSet(ruleNames), tags: toSet(tagNames)}
+	return f
+}
+
+func (f *filteredSink) Send(a Alert) error {
+	if !f.matches(a) {
+		return nil
+	}
+
+	return f.Sink.Send(a)
+}
+
+func (f *filteredSink) matches(a Alert) bool {
+	if len(f.rules) > 0 {
+		if !anyIn(f.rules, a.Rules) {
+			return false
+		}
+	}
+
+	if len(f.tags) > 0 {
+		tags := strings.Split(a.Tags, ",")
+		for i, t := range tags {
+			tags[i] = strings.TrimSpace(t)
+		}
+		if !anyIn(f.tags, tags) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toSet(vals []string) map[string]bool {
+	set := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		set[v] = true
+	}
+	return set
+}
+
+func anyIn(set map[string]bool, vals []string) bool {
+	for _, v := range vals {
+		if set[v] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import "testing"
+
+func TestFilteredSinkMatches(t *testing.T) {
+	alert := Alert{Rules: []string{"aws-key"}, Tags: "prod, leaked"}
+
+	cases := []struct {
+		name  string
+		rules []string
+		tags  []string
+		want  bool
+	}{
+		{"no filter matches anything", nil, nil, true},
+		{"rule-only filter, rule present", []string{"aws-key"}, nil, true},
+		{"rule-only filter, rule absent", []string{"email"}, nil, false},
+		{"tag-only filter, tag present", nil, []string{"prod"}, true},
+		{"tag-only filter, tag absent", nil, []string{"staging"}, false},
+		{"both filters match", []string{"aws-key"}, []string{"leaked"}, true},
+		{"both filters, rule fails", []string{"email"}, []string{"leaked"}, false},
+		{"both filters, tag fails", []string{"aws-key"}, []string{"staging"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := &filteredSink{Sink: &stdoutSink{}, rules: toSet(c.rules), tags: toSet(c.tags)}
+			if got := f.matches(alert); got != c.want {
+				t.Errorf("matches() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Known HMAC-SHA256 test vector: key "key", data "The quick brown fox
+	// jumps over the lazy dog", verified independently with
+	// `openssl dgst -sha256 -hmac key`.
+	got := sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+
+	if got != want {
+		t.Errorf("sign() = %q, want %q", got, want)
+	}
+}
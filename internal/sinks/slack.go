@@ -0,0 +1,65 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// slackSink posts a formatted attachment to a Slack incoming webhook.
+type slackSink struct {
+	url string
+}
+
+type slackPayload struct {
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Text   string       `json:"text,omitempty"`
+	Fields []slackField `json:"fields,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *slackSink) Send(a Alert) error {
+	payload := slackPayload{
+		Attachments: []slackAttachment{
+			{
+				Color: "warning",
+				Title: fmt.Sprintf("psbmon: paste %s matched %d rule(s)", a.ID, len(a.Rules)),
+				Text:  strings.Join(a.Rules, ", "),
+				Fields: []slackField{
+					{Title: "Tags", Value: a.Tags, Short: true},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return post(s.url, nil, body)
+}
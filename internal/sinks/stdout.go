@@ -0,0 +1,34 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// stdoutSink writes one JSON object per line to standard output, for
+// piping psbmon into other tooling without running Dr.Gero.
+type stdoutSink struct{}
+
+func (s *stdoutSink) Send(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(body))
+	return nil
+}
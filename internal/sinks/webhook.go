@@ -0,0 +1,53 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// webhookSink posts alerts as JSON to an arbitrary URL, optionally
+// signing the body the way GitHub does for its webhooks.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	secret  string
+}
+
+func (s *webhookSink) Send(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(s.headers)+1)
+	for k, v := range s.headers {
+		headers[k] = v
+	}
+	if s.secret != "" {
+		headers["X-Hub-Signature-256"] = "sha256=" + sign(s.secret, body)
+	}
+
+	return post(s.url, headers, body)
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
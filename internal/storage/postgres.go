@@ -0,0 +1,95 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"database/sql"
+	"log"
+
+	_ "github.com/lib/pq" // Postgres
+)
+
+// postgresStorage lets multiple psbmon instances share one central
+// database instead of each keeping its own SQLite file.
+type postgresStorage struct {
+	db  *sql.DB
+	url string
+}
+
+func newPostgresStorage(url string) (*postgresStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresStorage{db: db, url: url}, nil
+}
+
+func (p *postgresStorage) Init() error {
+	log.Println("Creating `pastes` table if it does not exist")
+
+	_, err := p.db.Exec(
+		"CREATE TABLE IF NOT EXISTS pastes (id SERIAL PRIMARY KEY, paste_id VARCHAR(255) NOT NULL UNIQUE)",
+	)
+	if err != nil {
+		log.Printf("failed to create `pastes` table: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (p *postgresStorage) Seen(id string) bool {
+	row := p.db.QueryRow("SELECT id FROM pastes WHERE paste_id=$1", id)
+
+	var rowid int
+	err := row.Scan(&rowid)
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		log.Printf("failed to query DB for paste_ID %s: %v", id, err)
+		return false
+	}
+
+	return true
+}
+
+func (p *postgresStorage) Add(id string) error {
+	res, err := p.db.Exec("INSERT INTO pastes(paste_id) VALUES($1) ON CONFLICT (paste_id) DO NOTHING", id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Another monitor sharing this DB already recorded id between our
+		// Seen() check and this INSERT; don't let the race double-send.
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (p *postgresStorage) Ping() error {
+	return p.db.Ping()
+}
+
+func (p *postgresStorage) Close() {
+	p.db.Close()
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStorageAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	p := &postgresStorage{db: db}
+
+	t.Run("new id inserts cleanly", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO pastes").
+			WithArgs("abc123").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		if err := p.Add("abc123"); err != nil {
+			t.Fatalf("Add() = %v, want nil", err)
+		}
+	})
+
+	t.Run("raced id already inserted by another writer", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO pastes").
+			WithArgs("dup456").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := p.Add("dup456")
+		if !errors.Is(err, ErrAlreadyExists) {
+			t.Fatalf("Add() = %v, want ErrAlreadyExists", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"database/sql"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3" // Sqlite
+)
+
+// sqliteStorage is the original local-file backend.
+type sqliteStorage struct {
+	db   *sql.DB
+	path string
+}
+
+func newSQLiteStorage(path string) (*sqliteStorage, error) {
+	if path == "" {
+		path = "./pastes.db"
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqliteStorage{db: db, path: path}, nil
+}
+
+func (s *sqliteStorage) Init() error {
+	log.Println("Creating `pastes` table if it does not exist")
+
+	stmt, err := s.db.Prepare(
+		"CREATE TABLE IF NOT EXISTS pastes (id INTEGER PRIMARY KEY, paste_ID VARCHAR(255) NOT NULL UNIQUE)",
+	)
+	if err != nil {
+		log.Printf("failed to prepare create table statement for `pastes` table: %v ", err)
+		return err
+	}
+	defer stmt.Close()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Printf("failed to begin transaction: %v", err)
+		return err
+	}
+
+	_, err = tx.Stmt(stmt).Exec()
+	if err != nil {
+		log.Printf("failed to create `pastes` table: %v", err)
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStorage) Seen(id string) bool {
+	stmt, err := s.db.Prepare("SELECT id FROM pastes WHERE paste_ID=?")
+	if err != nil {
+		log.Printf("failed to prepare seen query: %v", err)
+		return false
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRow(id)
+
+	var rowid int
+	err = row.Scan(&rowid)
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		log.Printf("failed to query DB for paste_ID %s: %v", id, err)
+		return false
+	}
+
+	return true
+}
+
+func (s *sqliteStorage) Add(id string) error {
+	stmt, err := s.db.Prepare("INSERT INTO pastes(paste_ID) VALUES(?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Stmt(stmt).Exec(id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStorage) Ping() error {
+	return s.db.Ping()
+}
+
+func (s *sqliteStorage) Close() {
+	s.db.Close()
+}
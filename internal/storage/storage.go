@@ -0,0 +1,88 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage provides a pluggable backend for tracking paste IDs
+// that psbmon has already seen.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAlreadyExists is returned by Add when id was already recorded by
+// another writer (e.g. a second monitor sharing the same Postgres
+// database) between the caller's Seen() check and this Add() call.
+// Callers should treat it like any other Add error and skip sending.
+var ErrAlreadyExists = errors.New("storage: id already exists")
+
+// Storage is the interface implemented by each backend psbmon can use to
+// keep track of which paste IDs have already been seen.
+type Storage interface {
+	// Init prepares the backend, creating any tables/schema it needs.
+	Init() error
+
+	// Seen reports whether id has already been recorded. Backend errors
+	// are logged and treated as "not seen" so a transient DB hiccup
+	// doesn't wedge the poll loop.
+	Seen(id string) bool
+
+	// Add records id as seen. It returns ErrAlreadyExists if another
+	// writer recorded id first.
+	Add(id string) error
+
+	// Ping reports whether the backend is reachable, for health checks.
+	Ping() error
+
+	// Close releases any resources held by the backend.
+	Close()
+}
+
+// defaultDBURL is used when neither --db nor DG_DB_URL is set, preserving
+// the historical on-disk SQLite file in the current working directory.
+const defaultDBURL = "sqlite://pastes.db"
+
+// Open selects and opens a Storage backend based on dbURL, which takes the
+// form "sqlite://path/to/file.db" (the path is relative to the current
+// working directory, as a leading "/" is not treated specially) or
+// "postgres://user:pw@host/db?sslmode=disable". An empty dbURL falls back
+// to the default local SQLite file.
+func Open(dbURL string) (Storage, error) {
+	if dbURL == "" {
+		dbURL = defaultDBURL
+	}
+
+	scheme := dbURL
+	if i := strings.Index(dbURL, "://"); i != -1 {
+		scheme = dbURL[:i]
+	}
+
+	switch scheme {
+	case "sqlite":
+		return newSQLiteStorage(sqlitePath(dbURL))
+	case "postgres", "postgresql":
+		return newPostgresStorage(dbURL)
+	default:
+		return nil, fmt.Errorf("storage: unsupported db scheme %q", scheme)
+	}
+}
+
+// sqlitePath extracts the filesystem path from a "sqlite://" URL. Unlike
+// a "file://" URL, a leading slash after the scheme is kept as part of a
+// relative path rather than being treated as the filesystem root, so
+// "sqlite://pastes.db" resolves to "pastes.db" in the cwd.
+func sqlitePath(dbURL string) string {
+	return strings.TrimPrefix(dbURL, "sqlite://")
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 Blacksun Research Labs
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import "testing"
+
+func TestSqlitePath(t *testing.T) {
+	cases := []struct {
+		dbURL string
+		want  string
+	}{
+		{"sqlite://pastes.db", "pastes.db"},
+		{"sqlite://./pastes.db", "./pastes.db"},
+		{"sqlite://data/pastes.db", "data/pastes.db"},
+		{defaultDBURL, "pastes.db"},
+	}
+
+	for _, c := range cases {
+		if got := sqlitePath(c.dbURL); got != c.want {
+			t.Errorf("sqlitePath(%q) = %q, want %q", c.dbURL, got, c.want)
+		}
+	}
+}
@@ -15,8 +15,6 @@ package main
 // limitations under the License.
 
 import (
-	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -27,7 +25,12 @@ import (
 	"time"
 
 	flags "github.com/jessevdk/go-flags"
-	_ "github.com/mattn/go-sqlite3" // Sqlite
+
+	"github.com/BlacksunLabs/dg-psbmon/internal/httpretry"
+	"github.com/BlacksunLabs/dg-psbmon/internal/metrics"
+	"github.com/BlacksunLabs/dg-psbmon/internal/rules"
+	"github.com/BlacksunLabs/dg-psbmon/internal/sinks"
+	"github.com/BlacksunLabs/dg-psbmon/internal/storage"
 )
 
 // ID is a single paste ID from the Daily API
@@ -47,94 +50,55 @@ type postBody struct {
 }
 
 type options struct {
-	Interval int `short:"i" long:"interval" description:"Time in minutes to wait before checking feeds" default:"5"`
+	Interval    int    `short:"i" long:"interval" description:"Time in minutes to wait before checking feeds" default:"5"`
+	DBURL       string `long:"db" description:"Storage backend URL, e.g. sqlite://pastes.db or postgres://user:pw@host/db?sslmode=disable" env:"DG_DB_URL"`
+	RulesPath   string `long:"rules" description:"Path to a YAML/JSON file of regex alert rules; if unset, every new paste is forwarded" env:"DG_RULES"`
+	MetricsAddr string `long:"metrics-addr" description:"Address to serve Prometheus metrics and /healthz, /readyz on, e.g. :9090" env:"DG_METRICS_ADDR"`
+	SinksPath   string `long:"sinks" description:"Path to a YAML/JSON sinks config file; if unset, alerts are forwarded to DG_HOST only" env:"DG_SINKS"`
 }
 
+// maxPollAge is how stale the last successful poll may be before /readyz
+// starts failing. It's a small multiple of the default interval so a
+// single slow poll doesn't flap readiness.
+const maxPollAge = 15 * time.Minute
+
 var hostString string
 var opts options
+var ruleset []*rules.Rule
+var sinkList []sinks.Sink
 var parser = flags.NewParser(&opts, flags.Default)
 
-func makeTables(db *sql.DB) error {
-	log.Println("Creating `pastes` table if it does not exist")
-
-	stmt, err := db.Prepare(
-		"CREATE TABLE IF NOT EXISTS pastes (id INTEGER PRIMARY KEY, paste_ID VARCHAR(255) NOT NULL UNIQUE)",
-	)
-	if err != nil {
-		log.Printf("failed to prepare create table statement for `pastes` table: %v ", err)
-	}
-	defer stmt.Close()
-
-	tx, err := db.Begin()
-	if err != nil {
-		log.Printf("failed to begin transaction: %v", err)
-	}
-
-	_, err = tx.Stmt(stmt).Exec()
-	if err != nil {
-		log.Fatalf("failed to create `pastes` table: %v", err)
-		tx.Rollback()
-	}
-	tx.Commit()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func open() (db *sql.DB, err error) {
-	db, err = sql.Open("sqlite3", "./pastes.db")
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
-}
-
 func getDaily() (p *Pastes, err error) {
 	url := "https://psbdmp.cc/api/v3/getbydate"
-	method := "POST"
 
 	year, month, day := time.Now().Date()
 	searchDate := fmt.Sprintf("from=%d.%d.%d&to=%d.%d.%d", day, int(month), year, day, int(month), year)
 
-	payload := strings.NewReader(searchDate)
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", url, strings.NewReader(searchDate))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Add("cache-control", "no-cache")
+		return req, nil
+	}
 
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, payload)
+	defer metrics.ObserveDuration("daily", time.Now())
 
+	res, err := httpretry.Do(nil, newRequest, httpretry.DefaultConfig())
 	if err != nil {
 		fmt.Println(err)
+		metrics.FetchErrors.WithLabelValues("daily").Inc()
 		return &Pastes{}, err
 	}
-
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("cache-control", "no-cache")
-
-	// Sometimes there are Cloudflare Gateway errors.
-	// Check for this condition and retry.
-	// I know this is ugly but it was late and I was too
-	// lazy to learn a retry package.
-	retries := 0
-	var res *http.Response
-	for retries < 3 {
-		res, err = client.Do(req)
-		if err != nil {
-			log.Println("Encountered error sending HTTP request:", err)
-			retries++
-		} else if res.StatusCode == 502 {
-			log.Printf("Encountered 502 error. Retry [%d/3]", retries+1)
-			retries++
-		} else {
-			break
-		}
-	}
 	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		fmt.Println(err)
+		metrics.FetchErrors.WithLabelValues("daily").Inc()
 		return
 	}
 
@@ -157,73 +121,58 @@ func getDaily() (p *Pastes, err error) {
 
 }
 
-func addID(db *sql.DB, pasteID string) error {
-	stmt, err := db.Prepare("INSERT INTO pastes(paste_ID) VALUES(?)")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+// pasteGet is the shape of a psbdmp.cc `/api/v3/get/<id>` response.
+type pasteGet struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Tags string `json:"tags"`
+}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return err
+func getPaste(id string) (*pasteGet, error) {
+	url := fmt.Sprintf("https://psbdmp.cc/api/v3/get/%s", id)
+
+	newRequest := func() (*http.Request, error) {
+		return http.NewRequest("GET", url, nil)
 	}
 
-	_, err = tx.Stmt(stmt).Exec(pasteID)
+	res, err := httpretry.Do(nil, newRequest, httpretry.DefaultConfig())
 	if err != nil {
-		tx.Rollback()
-		return err
+		return nil, err
 	}
-	tx.Commit()
-	return nil
-}
+	defer res.Body.Close()
 
-func checkID(db *sql.DB, id string) (ok bool, err error) {
-	stmt, err := db.Prepare("SELECT id FROM pastes WHERE paste_ID=?")
+	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	defer stmt.Close()
 
-	row := stmt.QueryRow(id)
-
-	var rowid int
-
-	err = row.Scan(&rowid)
-	if err != nil && err != sql.ErrNoRows {
-		return false, err
+	p := &pasteGet{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	return p, nil
 }
 
-func post(payload []byte, url string) error {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "psbmon")
+// send delivers id to every configured sink, stamping the alert with its
+// tags and any matched rules.
+func (id *ID) send(dest []sinks.Sink, tags string, matches []rules.Match) error {
+	defer metrics.ObserveDuration("send", time.Now())
 
-	client := &http.Client{}
-	_, err = client.Do(req)
-	if err != nil {
-		return err
+	a := sinks.Alert{ID: id.ID, Tags: tags, Matches: matches}
+	for _, m := range matches {
+		a.Rules = append(a.Rules, m.Rule)
 	}
 
-	return nil
-}
-
-func (id *ID) send(host string) error {
-	body, err := json.Marshal(id.ID)
-	if err != nil {
-		return err
-	}
-
-	url := fmt.Sprintf("%s/event", host)
-	err = post(body, url)
-	if err != nil {
-		return err
+	var lastErr error
+	for _, s := range dest {
+		if err := s.Send(a); err != nil {
+			metrics.FetchErrors.WithLabelValues("send").Inc()
+			lastErr = err
+		}
 	}
 
-	return nil
+	return lastErr
 }
 
 func init() {
@@ -235,25 +184,47 @@ func init() {
 			os.Exit(1)
 		}
 	}
+
+	if opts.RulesPath != "" {
+		ruleset, err = rules.Load(opts.RulesPath)
+		if err != nil {
+			log.Fatalf("failed to load alert rules: %v", err)
+		}
+	}
+
+	if opts.SinksPath != "" {
+		sinkList, err = sinks.Load(opts.SinksPath)
+		if err != nil {
+			log.Fatalf("failed to load sinks config: %v", err)
+		}
+	}
 }
 
 func main() {
-	db, err := open()
+	db, err := storage.Open(opts.DBURL)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 		return
 	}
+	defer db.Close()
 
-	err = makeTables(db)
+	err = db.Init()
 	if err != nil {
-		log.Fatalf("failed to create `pastes` table: %v", err)
+		log.Fatalf("failed to initialize storage backend: %v", err)
 	}
 
 	hostString = os.Getenv("DG_HOST")
 
-	if hostString == "" {
-		log.Fatal("Must provide Dr.Gero API host in DG_HOST environment variable")
-		return
+	if len(sinkList) == 0 {
+		if hostString == "" {
+			log.Fatal("Must provide Dr.Gero API host in DG_HOST environment variable, or configure sinks via --sinks")
+			return
+		}
+		sinkList = []sinks.Sink{sinks.NewDrGero(hostString)}
+	}
+
+	if opts.MetricsAddr != "" {
+		go metrics.Serve(opts.MetricsAddr, db.Ping, maxPollAge)
 	}
 
 	ticker := time.NewTicker(time.Duration(opts.Interval) * time.Minute).C
@@ -271,23 +242,49 @@ func main() {
 			}
 
 			for _, id := range p.Data {
-				ok, err := checkID(db, id.ID)
-				if err != nil || !ok {
-					log.Printf("failed to query DB for paste_ID: %s : %v", id.ID, err)
-					break
+				metrics.PastesSeen.Inc()
+
+				if db.Seen(id.ID) {
+					continue
 				}
-				err = addID(db, id.ID)
-				if err != nil && err.Error() != "UNIQUE constraint failed: pastes.paste_ID" {
+
+				err = db.Add(id.ID)
+				if err != nil {
 					log.Printf("error saving paste_ID to DB: %v", err)
 					continue
-				} else if err != nil && err.Error() == "UNIQUE constraint failed: pastes.paste_ID" {
-					continue
 				}
-				err = id.send(hostString)
+				metrics.PastesNew.Inc()
+
+				var matches []rules.Match
+				tags := id.Tags
+				if len(ruleset) > 0 {
+					paste, err := getPaste(id.ID)
+					if err != nil {
+						log.Printf("error fetching paste body for %s: %v", id.ID, err)
+						metrics.FetchErrors.WithLabelValues("paste").Inc()
+						continue
+					}
+
+					matches = rules.MatchAll(ruleset, paste.Text)
+					if len(matches) == 0 {
+						continue
+					}
+
+					// Prefer the per-paste tags from the get response, which
+					// can differ from (and are usually more complete than)
+					// the daily-listing tags on `id`.
+					if paste.Tags != "" {
+						tags = paste.Tags
+					}
+				}
+
+				err = id.send(sinkList, tags, matches)
 				if err != nil {
 					log.Println(err)
 				}
 			}
+
+			metrics.RecordSuccess(time.Now())
 		}
 	}
 }